@@ -2,21 +2,323 @@ package main
 
 import (
 	"context"
+	"errors"
 	"flag"
 	"fmt"
-	"log"
+	"log/slog"
+	"math/rand"
 	"os"
+	"regexp"
 	"strconv"
 	"strings"
+	"sync"
 	"time"
 
 	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+	"github.com/jackc/pgx/v5/pgxpool"
 
-	_ "github.com/jackc/pgx/v5"
-
+	"github.com/bixority/pg-maintenance/internal/module/config"
+	"github.com/bixority/pg-maintenance/internal/module/metrics"
 	"github.com/bixority/pg-maintenance/internal/module/pg"
 )
 
+// logger is the process-wide structured logger. Every log line is emitted
+// as JSON so it can be ingested directly by Loki/ELK-style log pipelines.
+var logger = slog.New(slog.NewJSONHandler(os.Stdout, nil))
+
+// fatalf logs msg at error level, counts it against pgmaint_errors_total,
+// and exits the process. It mirrors the log.Fatalf calls it replaces.
+func fatalf(msg string, args ...any) {
+	logger.Error(fmt.Sprintf(msg, args...))
+	metrics.ErrorsTotal.WithLabelValues("fatal").Inc()
+	os.Exit(1)
+}
+
+// dbConn is the subset of *pgx.Conn and *pgxpool.Conn that the cleanup
+// logic needs, so a single database connection and a pooled one can be
+// used interchangeably.
+type dbConn interface {
+	BeginTx(ctx context.Context, txOptions pgx.TxOptions) (pgx.Tx, error)
+	Exec(ctx context.Context, sql string, args ...interface{}) (pgconn.CommandTag, error)
+	Query(ctx context.Context, sql string, args ...interface{}) (pgx.Rows, error)
+	QueryRow(ctx context.Context, sql string, args ...interface{}) pgx.Row
+}
+
+// partitionMode controls whether the partition DETACH/DROP fast path is used
+// in place of row-level ctid batch deletes.
+const (
+	partitionModeAuto    = "auto"
+	partitionModeNever   = "never"
+	partitionModeRequire = "require"
+)
+
+// leafPartition describes one leaf partition of a RANGE-partitioned table,
+// together with the upper bound of its partition key recovered from
+// pg_get_expr(relpartbound, oid).
+type leafPartition struct {
+	id         pg.Identifier
+	upperBound time.Time
+	unbounded  bool
+}
+
+// partitionBoundRE extracts the upper bound literal from a single-column
+// RANGE partition bound expression, e.g.
+// "FOR VALUES FROM ('2024-01-01 00:00:00') TO ('2024-02-01 00:00:00')".
+var partitionBoundRE = regexp.MustCompile(`(?i)TO\s*\(\s*'([^']*)'\s*\)\s*$`)
+
+// isPartitionedByRange reports whether table is a declaratively
+// partitioned table using RANGE partitioning.
+func isPartitionedByRange(ctx context.Context, conn dbConn, table pg.Identifier) (bool, error) {
+	var strategy string
+
+	err := conn.QueryRow(
+		ctx,
+		`SELECT p.partstrat
+		 FROM pg_partitioned_table p
+		 JOIN pg_class c ON c.oid = p.partrelid
+		 JOIN pg_namespace n ON n.oid = c.relnamespace
+		 WHERE c.relname = $1 AND ($2 = '' OR n.nspname = $2)`,
+		table.Name,
+		table.Schema,
+	).Scan(&strategy)
+
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return false, nil
+		}
+
+		return false, err
+	}
+
+	return strategy == "r", nil
+}
+
+// listLeafPartitions enumerates the leaf partitions of a RANGE-partitioned
+// table and recovers each one's upper bound timestamp. Partitions whose
+// bound can't be parsed as a single upper-bound literal (e.g. the catch-all
+// MAXVALUE partition) are returned with unbounded=true.
+func listLeafPartitions(ctx context.Context, conn dbConn, table pg.Identifier) ([]leafPartition, error) {
+	rows, err := conn.Query(
+		ctx,
+		`SELECT child.relname, cn.nspname, pg_get_expr(child.relpartbound, child.oid)
+		 FROM pg_inherits i
+		 JOIN pg_class parent ON parent.oid = i.inhparent
+		 JOIN pg_namespace pn ON pn.oid = parent.relnamespace
+		 JOIN pg_class child ON child.oid = i.inhrelid
+		 JOIN pg_namespace cn ON cn.oid = child.relnamespace
+		 WHERE parent.relname = $1
+		   AND ($2 = '' OR pn.nspname = $2)
+		   AND NOT EXISTS (
+		       SELECT 1 FROM pg_partitioned_table pt WHERE pt.partrelid = child.oid
+		   )`,
+		table.Name,
+		table.Schema,
+	)
+
+	if err != nil {
+		return nil, err
+	}
+
+	defer rows.Close()
+
+	var partitions []leafPartition
+
+	for rows.Next() {
+		var name string
+		var schema string
+		var bound string
+
+		if err := rows.Scan(&name, &schema, &bound); err != nil {
+			return nil, err
+		}
+
+		id := pg.Identifier{Schema: schema, Name: name}
+
+		match := partitionBoundRE.FindStringSubmatch(bound)
+
+		if match == nil {
+			partitions = append(partitions, leafPartition{id: id, unbounded: true})
+
+			continue
+		}
+
+		upperBound, err := time.Parse("2006-01-02 15:04:05", match[1])
+
+		if err != nil {
+			upperBound, err = time.Parse("2006-01-02", match[1])
+		}
+
+		if err != nil {
+			partitions = append(partitions, leafPartition{id: id, unbounded: true})
+
+			continue
+		}
+
+		partitions = append(partitions, leafPartition{id: id, upperBound: upperBound})
+	}
+
+	return partitions, rows.Err()
+}
+
+// pruneExpiredPartitions detaches and drops every leaf partition of table
+// whose upper bound is strictly before cutoff. It returns the number of
+// partitions dropped.
+func pruneExpiredPartitions(ctx context.Context, conn dbConn, table pg.Identifier, cutoff time.Time) (int, error) {
+	partitions, err := listLeafPartitions(ctx, conn, table)
+	quotedTable := pg.Quote(table)
+
+	if err != nil {
+		return 0, fmt.Errorf("failed to enumerate partitions of %s: %w", quotedTable, err)
+	}
+
+	dropped := 0
+
+	for _, partition := range partitions {
+		if partition.unbounded || !partition.upperBound.Before(cutoff) {
+			continue
+		}
+
+		quotedPartition := pg.Quote(partition.id)
+
+		detachQuery := fmt.Sprintf(
+			`ALTER TABLE %s DETACH PARTITION %s CONCURRENTLY`,
+			quotedTable,
+			quotedPartition,
+		)
+
+		logger.Info(detachQuery)
+
+		if _, err := conn.Exec(ctx, detachQuery); err != nil {
+			return dropped, fmt.Errorf("failed to detach partition %s: %w", quotedPartition, err)
+		}
+
+		dropQuery := fmt.Sprintf(`DROP TABLE %s`, quotedPartition)
+
+		logger.Info(dropQuery)
+
+		if _, err := conn.Exec(ctx, dropQuery); err != nil {
+			return dropped, fmt.Errorf("failed to drop detached partition %s: %w", quotedPartition, err)
+		}
+
+		dropped++
+
+		logger.Info(fmt.Sprintf("Dropped expired partition %s (upper bound %s)", quotedPartition, partition.upperBound))
+	}
+
+	return dropped, nil
+}
+
+// dryRunReport summarizes the rows that a cleanup run would delete from a
+// single table, without taking row locks or blocking writers.
+type dryRunReport struct {
+	tableName      string
+	rowCount       int64
+	oldest         *time.Time
+	newest         *time.Time
+	estimatedBytes int64
+	sampleCtids    []string
+}
+
+// runDryRun evaluates the cleanup predicate for tableName inside a
+// SERIALIZABLE READ ONLY DEFERRABLE transaction (a snapshot transaction that
+// takes no row locks and never blocks or is blocked by concurrent writers),
+// then rolls back. sampleSize caps how many ctids are reported for operator
+// review.
+func runDryRun(
+	ctx context.Context,
+	conn dbConn,
+	table config.TablePolicy,
+	cutoff time.Time,
+	sampleSize int,
+) (dryRunReport, error) {
+	tableName := table.QualifiedName()
+	timestampColumn := pg.Quote(pg.Identifier{Name: table.TimestampColumn})
+	report := dryRunReport{tableName: tableName}
+	predicate := fmt.Sprintf("%s < $1", timestampColumn)
+
+	if table.WhereExtra != "" {
+		predicate += fmt.Sprintf(" AND (%s)", table.WhereExtra)
+	}
+
+	tx, err := conn.BeginTx(ctx, pgx.TxOptions{
+		IsoLevel:       pgx.Serializable,
+		AccessMode:     pgx.ReadOnly,
+		DeferrableMode: pgx.Deferrable,
+	})
+
+	if err != nil {
+		return report, fmt.Errorf("failed to begin read-only snapshot transaction: %w", err)
+	}
+
+	defer func() {
+		_ = tx.Rollback(ctx)
+	}()
+
+	summaryQuery := fmt.Sprintf(
+		`SELECT count(*), min(%s), max(%s), coalesce(sum(pg_column_size(t.*)), 0)
+		 FROM %s t WHERE %s`,
+		timestampColumn,
+		timestampColumn,
+		tableName,
+		predicate,
+	)
+
+	if err := tx.QueryRow(ctx, summaryQuery, cutoff).Scan(
+		&report.rowCount,
+		&report.oldest,
+		&report.newest,
+		&report.estimatedBytes,
+	); err != nil {
+		return report, fmt.Errorf("failed to summarize candidate rows in %s: %w", tableName, err)
+	}
+
+	sampleQuery := fmt.Sprintf(
+		`SELECT ctid FROM %s WHERE %s ORDER BY %s LIMIT $2`,
+		tableName,
+		predicate,
+		timestampColumn,
+	)
+
+	rows, err := tx.Query(ctx, sampleQuery, cutoff, sampleSize)
+
+	if err != nil {
+		return report, fmt.Errorf("failed to sample candidate rows in %s: %w", tableName, err)
+	}
+
+	defer rows.Close()
+
+	for rows.Next() {
+		var ctid string
+
+		if err := rows.Scan(&ctid); err != nil {
+			return report, fmt.Errorf("failed to scan sampled ctid in %s: %w", tableName, err)
+		}
+
+		report.sampleCtids = append(report.sampleCtids, ctid)
+	}
+
+	if err := rows.Err(); err != nil {
+		return report, err
+	}
+
+	return report, nil
+}
+
+// logDryRunReport prints a dry-run report in the same style as the regular
+// per-batch log lines.
+func logDryRunReport(report dryRunReport) {
+	logger.Info(fmt.Sprintf(
+		"[dry-run] %s: %d row(s) would be deleted, oldest=%v newest=%v estimated_bytes_reclaimed=%d",
+		report.tableName,
+		report.rowCount,
+		report.oldest,
+		report.newest,
+		report.estimatedBytes,
+	))
+	logger.Info(fmt.Sprintf("[dry-run] %s: sample ctids: %v", report.tableName, report.sampleCtids))
+}
+
 type arrayFlags []string
 
 // String is an implementation of the flag.Value interface
@@ -31,196 +333,803 @@ func (i *arrayFlags) Set(value string) error {
 	return nil
 }
 
-func main() {
-	now := time.Now()
-	dbUsername := os.Getenv("DB_USERNAME")
-	dbPassword := os.Getenv("DB_PASSWORD")
+// runOptions bundles the flags that apply uniformly to every table,
+// regardless of whether it came from --config or from --table shortcuts.
+type runOptions struct {
+	timeout       time.Duration
+	partitionMode string
+	dryRun        bool
+	dryRunSample  int
+	workers       int
+	connect       connectOptions
+	bloatReport   bool
+}
+
+// cleanupTable cleans up a single table policy. Under --dry-run it only
+// logs a dry-run report and returns, without pruning or deleting anything.
+// Otherwise it runs the partition-aware prune (when enabled) followed by
+// the row-level ctid batch DELETE loop. The DELETE loop always runs after
+// the prune step: the prune is the fast DETACH/DROP path for whole expired
+// partitions, and the DELETE loop is the row-level fallback for any table
+// (or straddling partition) the prune step didn't fully cover.
+func cleanupTable(ctx context.Context, conn dbConn, now time.Time, dbName string, table config.TablePolicy, opts runOptions) {
+	tableName := table.QualifiedName()
+
+	logger.Info(fmt.Sprintf(
+		"Cleaning up table %s by column %s for the records older than %d days with batch=%d",
+		tableName,
+		table.TimestampColumn,
+		table.RetentionDays,
+		table.BatchSize,
+	), "db", dbName, "schema", table.Schema, "table", table.Name)
+
+	cutoff := now.AddDate(0, 0, -table.RetentionDays)
+
+	if opts.dryRun {
+		report, err := runDryRun(ctx, conn, table, cutoff, opts.dryRunSample)
+
+		if err != nil {
+			fatalf("Dry run failed for %s: %v", tableName, err)
+		}
+
+		logDryRunReport(report)
+
+		return
+	}
+
+	if opts.bloatReport {
+		if before, err := collectBloatStats(ctx, conn, table.Identifier()); err != nil {
+			logger.Warn(fmt.Sprintf("Failed to collect bloat stats for %s: %v", tableName, err))
+		} else {
+			logBloatStats(dbName, table, "before cleanup", before)
+		}
+	}
+
+	if opts.partitionMode != partitionModeNever {
+		partitioned, err := isPartitionedByRange(ctx, conn, table.Identifier())
+
+		if err != nil {
+			fatalf("Failed to inspect partitioning of %s: %v", tableName, err)
+		}
+
+		if !partitioned {
+			if opts.partitionMode == partitionModeRequire {
+				fatalf("%s is not a RANGE-partitioned table, but --partition-mode=require", tableName)
+			}
+		} else {
+			dropped, err := pruneExpiredPartitions(ctx, conn, table.Identifier(), cutoff)
+
+			if err != nil {
+				fatalf("Failed to prune partitions of %s: %v", tableName, err)
+			}
+
+			logger.Info(fmt.Sprintf("Dropped %d expired partition(s) of %s", dropped, tableName))
+		}
+	}
+
+	// The row-level DELETE loop always runs after the prune step above: it's
+	// a no-op against partitions DETACH/DROP already removed, but it's the
+	// only thing that catches rows that are expired but still live in a
+	// partition whose own upper bound hasn't passed the cutoff yet (e.g. a
+	// monthly partition with a multi-day retention window).
+	deleteExpiredRowBatches(ctx, conn, dbName, table, tableName, cutoff, opts)
+
+	if opts.bloatReport {
+		if after, err := collectBloatStats(ctx, conn, table.Identifier()); err != nil {
+			logger.Warn(fmt.Sprintf("Failed to collect bloat stats for %s: %v", tableName, err))
+		} else {
+			logBloatStats(dbName, table, "after cleanup", after)
+		}
+	}
+
+	runVacuum(ctx, conn, dbName, table)
+
+	if opts.bloatReport && table.VacuumAfter != config.VacuumNone {
+		if after, err := collectBloatStats(ctx, conn, table.Identifier()); err != nil {
+			logger.Warn(fmt.Sprintf("Failed to collect bloat stats for %s: %v", tableName, err))
+		} else {
+			logBloatStats(dbName, table, "after vacuum", after)
+		}
+	}
+}
+
+// deleteExpiredRowBatches runs the row-level ctid batch DELETE loop against
+// tableName. It runs unconditionally after the partition prune step: for an
+// unpartitioned table (or --partition-mode=never) it's the only cleanup
+// path, and for a partitioned table it's a no-op against partitions
+// DETACH/DROP already removed but still catches expired rows left behind
+// in a partition whose own upper bound hasn't passed the cutoff yet.
+func deleteExpiredRowBatches(ctx context.Context, conn dbConn, dbName string, table config.TablePolicy, tableName string, cutoff time.Time, opts runOptions) {
+	args := []interface{}{cutoff}
+
+	if table.BatchSize > 0 {
+		args = append(args, table.BatchSize)
+	}
+
+	timestampColumn := pg.Quote(pg.Identifier{Name: table.TimestampColumn})
+	predicate := fmt.Sprintf("%s < $1", timestampColumn)
+
+	if table.WhereExtra != "" {
+		predicate += fmt.Sprintf(" AND (%s)", table.WhereExtra)
+	}
+
+	subquery := fmt.Sprintf(
+		`SELECT ctid FROM %s WHERE %s ORDER BY %s`,
+		tableName,
+		predicate,
+		timestampColumn,
+	)
+
+	if table.BatchSize > 0 {
+		subquery += " LIMIT $2"
+	}
+
+	query := fmt.Sprintf(`DELETE FROM %s WHERE ctid IN (%s);`, tableName, subquery)
+
+	batchNum := 0
+
+	for {
+		batchNum++
+		batchStart := time.Now()
+
+		var batchCtx context.Context
+		var cancel context.CancelFunc
+
+		if opts.timeout > 0 {
+			batchCtx, cancel = context.WithTimeout(ctx, opts.timeout)
+		} else {
+			batchCtx = context.WithoutCancel(ctx)
+			cancel = nil
+		}
+
+		tx, err := conn.BeginTx(batchCtx, pgx.TxOptions{})
+
+		if err != nil {
+			fatalf("Failed to begin transaction: %v", err)
+		}
+
+		if err := setLocalTimeouts(batchCtx, tx, table); err != nil {
+			_ = tx.Rollback(batchCtx)
+			fatalf("Failed to set statement/lock timeout for %s: %v", tableName, err)
+		}
+
+		logger.Info(query, "db", dbName, "schema", table.Schema, "table", table.Name, "batch", batchNum)
+
+		result, err := tx.Exec(batchCtx, query, args...)
+
+		if err != nil {
+			_ = tx.Rollback(batchCtx)
+
+			if cancel != nil {
+				cancel()
+			}
+
+			if isRetryableBatchError(err) {
+				metrics.ErrorsTotal.WithLabelValues("retryable_batch").Inc()
+				logger.Warn(fmt.Sprintf("Batch against %s canceled (%v); continuing to the next batch", tableName, err),
+					"db", dbName, "schema", table.Schema, "table", table.Name, "batch", batchNum)
+
+				continue
+			}
+
+			fatalf("Failed to execute query: %v", err)
+		}
 
-	if dbUsername == "" {
-		log.Fatal("Environment variable DB_USERNAME is required")
+		rowsAffected := result.RowsAffected()
+
+		if err := tx.Commit(batchCtx); err != nil {
+			fatalf("Failed to commit transaction: %v", err)
+		}
+
+		if cancel != nil {
+			cancel()
+		}
+
+		duration := time.Since(batchStart)
+		metrics.BatchesTotal.Inc()
+		metrics.BatchDurationSeconds.Observe(duration.Seconds())
+		metrics.RowsDeletedTotal.WithLabelValues(dbName, table.Schema, table.Name).Add(float64(rowsAffected))
+
+		if rowsAffected == 0 {
+			logger.Info("No more rows to delete. Exiting.", "db", dbName, "schema", table.Schema, "table", table.Name)
+
+			break
+		}
+
+		logger.Info(fmt.Sprintf("Deleted %d rows", rowsAffected),
+			"db", dbName, "schema", table.Schema, "table", table.Name,
+			"batch", batchNum, "rows", rowsAffected, "duration_ms", duration.Milliseconds())
+
+		if table.BatchSize == 0 {
+			break
+		}
+	}
+}
+
+// bloatStats is a point-in-time snapshot of a table's heap size and
+// live/dead tuple estimates, pulled from the standard pgstattuple-style
+// estimate query: pg_relation_size and pg_stat_user_tables.
+type bloatStats struct {
+	relationBytes int64
+	liveTuples    int64
+	deadTuples    int64
+}
+
+// collectBloatStats samples table's current size and tuple estimates using
+// the standard pgstattuple-style estimate query.
+func collectBloatStats(ctx context.Context, conn dbConn, table pg.Identifier) (bloatStats, error) {
+	var stats bloatStats
+
+	err := conn.QueryRow(
+		ctx,
+		`SELECT pg_relation_size(c.oid),
+		        coalesce(s.n_live_tup, 0),
+		        coalesce(s.n_dead_tup, 0)
+		 FROM pg_class c
+		 JOIN pg_namespace n ON n.oid = c.relnamespace
+		 LEFT JOIN pg_stat_user_tables s ON s.relid = c.oid
+		 WHERE c.relname = $1 AND ($2 = '' OR n.nspname = $2)`,
+		table.Name,
+		table.Schema,
+	).Scan(&stats.relationBytes, &stats.liveTuples, &stats.deadTuples)
+
+	return stats, err
+}
+
+// logBloatStats prints a bloat report line for a table at a given point in
+// the cleanup run (e.g. "before cleanup", "after vacuum").
+func logBloatStats(dbName string, table config.TablePolicy, when string, stats bloatStats) {
+	tableName := table.QualifiedName()
+
+	logger.Info(fmt.Sprintf(
+		"[bloat-report] %s %s: relation_size_bytes=%d live_tuples=%d dead_tuples=%d",
+		tableName, when, stats.relationBytes, stats.liveTuples, stats.deadTuples,
+	), "db", dbName, "schema", table.Schema, "table", table.Name)
+}
+
+// vacuumStats is a point-in-time snapshot of a table's planner row
+// estimate and total on-disk size, pulled from pg_class.reltuples and
+// pg_total_relation_size. It's the cheap, always-on companion to the
+// pgstattuple-style bloatStats gathered under --bloat-report.
+type vacuumStats struct {
+	estimatedRows int64
+	totalBytes    int64
+}
+
+// collectVacuumStats samples table's current pg_class.reltuples row
+// estimate and pg_total_relation_size.
+func collectVacuumStats(ctx context.Context, conn dbConn, table pg.Identifier) (vacuumStats, error) {
+	var (
+		stats         vacuumStats
+		estimatedRows float64
+	)
+
+	err := conn.QueryRow(
+		ctx,
+		`SELECT c.reltuples, pg_total_relation_size(c.oid)
+		 FROM pg_class c
+		 JOIN pg_namespace n ON n.oid = c.relnamespace
+		 WHERE c.relname = $1 AND ($2 = '' OR n.nspname = $2)`,
+		table.Name,
+		table.Schema,
+	).Scan(&estimatedRows, &stats.totalBytes)
+
+	stats.estimatedRows = int64(estimatedRows)
+
+	return stats, err
+}
+
+// logVacuumSummary prints a before/after VACUUM summary line for table
+// (estimated row count and total relation size) and records its size
+// under the matching phase label.
+func logVacuumSummary(dbName string, table config.TablePolicy, when string, stats vacuumStats) {
+	tableName := table.QualifiedName()
+
+	logger.Info(fmt.Sprintf(
+		"[vacuum] %s %s: estimated_rows=%d total_size_bytes=%d",
+		tableName, when, stats.estimatedRows, stats.totalBytes,
+	), "db", dbName, "schema", table.Schema, "table", table.Name)
+
+	metrics.TableSizeBytes.WithLabelValues(dbName, table.Schema, table.Name, when).Set(float64(stats.totalBytes))
+}
+
+// runVacuum runs the VACUUM variant selected by table.VacuumAfter against
+// table, outside of any transaction (Postgres disallows VACUUM inside one),
+// and logs a before/after row-estimate and size summary regardless of
+// whether --bloat-report is also set. Failures are logged, not fatal: a
+// stuck vacuum shouldn't fail a cleanup run that already committed its
+// deletes.
+func runVacuum(ctx context.Context, conn dbConn, dbName string, table config.TablePolicy) {
+	var query string
+
+	switch table.VacuumAfter {
+	case config.VacuumNone, "":
+		return
+	case config.VacuumAnalyze:
+		query = fmt.Sprintf("VACUUM (ANALYZE, VERBOSE) %s", table.QualifiedName())
+	case config.VacuumVacuum:
+		query = fmt.Sprintf("VACUUM (VERBOSE) %s", table.QualifiedName())
+	case config.VacuumFull:
+		query = fmt.Sprintf("VACUUM (FULL, VERBOSE) %s", table.QualifiedName())
+	default:
+		logger.Warn(fmt.Sprintf("Unknown vacuum_after %q for %s, skipping", table.VacuumAfter, table.QualifiedName()))
+
+		return
+	}
+
+	if before, err := collectVacuumStats(ctx, conn, table.Identifier()); err != nil {
+		logger.Warn(fmt.Sprintf("Failed to collect vacuum stats for %s: %v", table.QualifiedName(), err))
+	} else {
+		logVacuumSummary(dbName, table, "before vacuum", before)
 	}
 
-	if dbPassword == "" {
-		log.Fatal("Environment variable DB_PASSWORD is required")
+	logger.Info(query)
+
+	if _, err := conn.Exec(ctx, query); err != nil {
+		metrics.ErrorsTotal.WithLabelValues("vacuum").Inc()
+		logger.Warn(fmt.Sprintf("%s failed for %s: %v", query, table.QualifiedName(), err))
+
+		return
 	}
 
+	if after, err := collectVacuumStats(ctx, conn, table.Identifier()); err != nil {
+		logger.Warn(fmt.Sprintf("Failed to collect vacuum stats for %s: %v", table.QualifiedName(), err))
+	} else {
+		logVacuumSummary(dbName, table, "after vacuum", after)
+	}
+}
+
+// pgErrorCodeQueryCanceled and pgErrorCodeLockNotAvailable are the SQLSTATE
+// codes raised when a statement_timeout or lock_timeout fires mid-batch.
+const (
+	pgErrorCodeQueryCanceled     = "57014"
+	pgErrorCodeLockNotAvailable = "55P03"
+)
+
+// isRetryableBatchError reports whether err is a query_canceled or
+// lock_not_available error, in which case the caller should move on to the
+// next batch instead of aborting the whole job.
+func isRetryableBatchError(err error) bool {
+	var pgErr *pgconn.PgError
+
+	if !errors.As(err, &pgErr) {
+		return false
+	}
+
+	return pgErr.Code == pgErrorCodeQueryCanceled || pgErr.Code == pgErrorCodeLockNotAvailable
+}
+
+// setLocalTimeouts applies table's per-statement and per-lock timeouts to
+// tx via SET LOCAL, so they're scoped to the current transaction only.
+func setLocalTimeouts(ctx context.Context, tx pgx.Tx, table config.TablePolicy) error {
+	if table.StatementTimeout > 0 {
+		if _, err := tx.Exec(
+			ctx,
+			fmt.Sprintf("SET LOCAL statement_timeout = '%dms'", table.StatementTimeout.Milliseconds()),
+		); err != nil {
+			return err
+		}
+	}
+
+	if table.LockTimeout > 0 {
+		if _, err := tx.Exec(
+			ctx,
+			fmt.Sprintf("SET LOCAL lock_timeout = '%dms'", table.LockTimeout.Milliseconds()),
+		); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// connectOptions controls the capped exponential backoff retry loop used
+// to establish each database connection pool.
+type connectOptions struct {
+	retries        int
+	backoffInitial time.Duration
+	backoffMax     time.Duration
+	backoffJitter  float64
+}
+
+// connectWithRetry opens a connection pool against dsn, retrying up to
+// opts.retries times on failure (including a failed ping) with capped
+// exponential backoff: the delay doubles on each failure, is clamped to
+// opts.backoffMax, and gets uniform jitter added in
+// [0, opts.backoffJitter*backoff).
+func connectWithRetry(ctx context.Context, dsn string, opts connectOptions) (*pgxpool.Pool, error) {
+	backoff := opts.backoffInitial
+	var lastErr error
+
+	for attempt := 0; attempt <= opts.retries; attempt++ {
+		pool, err := pgxpool.New(ctx, dsn)
+
+		if err == nil {
+			pingCtx, cancel := context.WithTimeout(ctx, 10*time.Second)
+			err = pool.Ping(pingCtx)
+			cancel()
+
+			if err == nil {
+				return pool, nil
+			}
+
+			pool.Close()
+		}
+
+		lastErr = err
+		metrics.ErrorsTotal.WithLabelValues("connect").Inc()
+
+		if attempt == opts.retries {
+			break
+		}
+
+		sleep := backoff
+
+		if opts.backoffJitter > 0 {
+			sleep += time.Duration(rand.Float64() * opts.backoffJitter * float64(backoff))
+		}
+
+		logger.Warn(fmt.Sprintf(
+			"Connection attempt %d/%d failed: %v; retrying in %s",
+			attempt+1, opts.retries+1, err, sleep,
+		))
+
+		select {
+		case <-time.After(sleep):
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		}
+
+		backoff *= 2
+
+		if backoff > opts.backoffMax {
+			backoff = opts.backoffMax
+		}
+	}
+
+	return nil, fmt.Errorf("failed to connect after %d attempt(s): %w", opts.retries+1, lastErr)
+}
+
+// cleanupDatabase connects to a single database and runs every table
+// policy against it, bounded to opts.workers concurrent tables.
+func cleanupDatabase(ctx context.Context, now time.Time, db config.DatabasePolicy, opts runOptions) {
+	username := os.Getenv(db.UsernameEnv)
+	password := os.Getenv(db.PasswordEnv)
+
+	if username == "" {
+		fatalf("Environment variable %s is required", db.UsernameEnv)
+	}
+
+	if password == "" {
+		fatalf("Environment variable %s is required", db.PasswordEnv)
+	}
+
+	dbDSN := fmt.Sprintf(
+		`host=%s port=%d dbname=%s user=%s password=%s sslmode=%s`,
+		db.Host,
+		db.Port,
+		db.DBName,
+		username,
+		password,
+		db.SSLMode,
+	)
+
+	pool, err := connectWithRetry(ctx, dbDSN, opts.connect)
+
+	if err != nil {
+		fatalf("Failed to connect to database %s: %v", db.DBName, err)
+	}
+
+	defer pool.Close()
+
+	logger.Info(fmt.Sprintf("Connected to database %s successfully", db.DBName), "db", db.DBName)
+
+	workers := opts.workers
+
+	if workers < 1 {
+		workers = 1
+	}
+
+	sem := make(chan struct{}, workers)
+	var wg sync.WaitGroup
+
+	for _, table := range db.Tables {
+		table := table
+
+		wg.Add(1)
+		sem <- struct{}{}
+
+		go func() {
+			defer wg.Done()
+			defer func() { <-sem }()
+
+			conn, err := pool.Acquire(ctx)
+
+			if err != nil {
+				fatalf("Failed to acquire connection for %s: %v", table.QualifiedName(), err)
+			}
+
+			defer conn.Release()
+
+			cleanupTable(ctx, conn, now, db.DBName, table, opts)
+		}()
+	}
+
+	wg.Wait()
+}
+
+func main() {
+	now := time.Now()
+
 	var host string
 	var port int
 	var sslMode string
 	var dbName string
-	var tableName string
-	var timestampColumn string
-	var days int
+	var configPath string
 	var batchSize int
 	var timeout time.Duration
 	var tables arrayFlags
+	var partitionMode string
+	var dryRun bool
+	var dryRunSample int
+	var workers int
+	var statementTimeout time.Duration
+	var lockTimeout time.Duration
+	var connectRetries int
+	var connectBackoffInitial time.Duration
+	var connectBackoffMax time.Duration
+	var connectBackoffJitter float64
+	var vacuumAfter string
+	var bloatReport bool
+	var metricsAddr string
+	var pushgatewayURL string
 
 	flag.StringVar(&host, "host", "localhost", "Database host")
 	flag.IntVar(&port, "port", 5432, "Database port")
 	flag.StringVar(&sslMode, "sslMode", "require", "SSL mode")
 	flag.StringVar(&dbName, "dbName", "", "Database name")
+	flag.StringVar(
+		&configPath,
+		"config",
+		"",
+		"Path to a YAML policy file describing multiple databases and tables. "+
+			"When set, --dbName/--table/--batch are ignored.",
+	)
 	flag.Var(&tables, "table", "Table(s) in a table:[timestampColumn=created_at[:days=0]] format.")
 	flag.IntVar(&batchSize, "batch", 0, "Optional batch size for cleanup")
 	flag.DurationVar(&timeout, "timeout", 60*time.Second, "Single db operation timeout in seconds")
+	flag.StringVar(
+		&partitionMode,
+		"partition-mode",
+		partitionModeAuto,
+		"Partition pruning strategy: auto (DETACH/DROP expired partitions, fall back to row "+
+			"deletes for the rest), never (always use row-level deletes), require (error if the "+
+			"table isn't RANGE partitioned)",
+	)
+	flag.BoolVar(
+		&dryRun,
+		"dry-run",
+		false,
+		"Evaluate the cleanup predicate inside a read-only snapshot transaction and report what "+
+			"would be deleted, without deleting anything",
+	)
+	flag.IntVar(&dryRunSample, "dry-run-sample", 20, "Number of sample ctids to report in --dry-run")
+	flag.IntVar(&workers, "workers", 4, "Maximum number of tables to clean up concurrently per database")
+	flag.DurationVar(
+		&statementTimeout,
+		"statement-timeout",
+		0,
+		"SET LOCAL statement_timeout applied to each DELETE batch (0 disables it); "+
+			"ignored when --config is set, where statement_timeout is set per table",
+	)
+	flag.DurationVar(
+		&lockTimeout,
+		"lock-timeout",
+		0,
+		"SET LOCAL lock_timeout applied to each DELETE batch (0 disables it); "+
+			"ignored when --config is set, where lock_timeout is set per table",
+	)
+	flag.IntVar(&connectRetries, "connect-retries", 5, "Number of extra connection attempts before giving up")
+	flag.DurationVar(&connectBackoffInitial, "connect-backoff-initial", 500*time.Millisecond, "Initial connection retry backoff")
+	flag.DurationVar(&connectBackoffMax, "connect-backoff-max", 30*time.Second, "Maximum connection retry backoff")
+	flag.Float64Var(
+		&connectBackoffJitter,
+		"connect-backoff-jitter",
+		0.5,
+		"Fraction of the backoff duration to add as uniform random jitter",
+	)
+	flag.StringVar(
+		&vacuumAfter,
+		"vacuum",
+		string(config.VacuumNone),
+		"Vacuum strategy to run against a table once its cleanup finishes: none, analyze "+
+			"(VACUUM (ANALYZE, VERBOSE)), vacuum (VACUUM (VERBOSE)), or full (VACUUM (FULL, VERBOSE)); "+
+			"ignored when --config is set, where vacuum_after is set per table",
+	)
+	flag.BoolVar(
+		&bloatReport,
+		"bloat-report",
+		false,
+		"Log table size and live/dead tuple estimates before cleanup and before/after vacuum",
+	)
+	flag.StringVar(
+		&metricsAddr,
+		"metrics-addr",
+		"",
+		"If set, serve Prometheus metrics on this address (e.g. :9090) until the run finishes",
+	)
+	flag.StringVar(
+		&pushgatewayURL,
+		"pushgateway",
+		"",
+		"If set, push metrics to this Prometheus Pushgateway URL after the run finishes, "+
+			"for one-shot cron invocations that exit before a scrape could happen",
+	)
 	flag.Parse()
 
-	if dbName == "" || len(tables) == 0 {
-		log.Fatalln("All --dbName and --table arguments are required")
+	switch partitionMode {
+	case partitionModeAuto, partitionModeNever, partitionModeRequire:
+	default:
+		fatalf(
+			"Unsupported partition-mode %s, \"auto\" (default), \"never\", and \"require\" supported",
+			partitionMode,
+		)
 	}
 
 	if sslMode != "require" && sslMode != "disable" && sslMode != "verify-full" && sslMode != "verify-cy" {
-		log.Fatalf(
+		fatalf(
 			"Unsupported sslMode %s, \"require\" (default), "+
-				"\"verify-full\", \"verify-ca\", and \"disable\" supported\n",
+				"\"verify-full\", \"verify-ca\", and \"disable\" supported",
 			sslMode,
 		)
 	}
 
-	var dbDSN = fmt.Sprintf(
-		`host=%s port=%d dbname=%s user=%s password=%s sslmode=%s`,
-		host,
-		port,
-		dbName,
-		dbUsername,
-		dbPassword,
-		sslMode,
-	)
+	switch config.VacuumMode(vacuumAfter) {
+	case config.VacuumNone, config.VacuumAnalyze, config.VacuumVacuum, config.VacuumFull:
+	default:
+		fatalf(
+			"Unsupported vacuum %s, \"none\" (default), \"analyze\", \"vacuum\", and \"full\" supported",
+			vacuumAfter,
+		)
+	}
 
-	conn, err := pgx.Connect(context.Background(), dbDSN)
+	if metricsAddr != "" {
+		server := metrics.Serve(metricsAddr)
+		defer server.Close()
 
-	if err != nil {
-		log.Fatalf("ERROR: Failed to connect to database: %v\n", err)
+		logger.Info(fmt.Sprintf("Serving Prometheus metrics on %s/metrics", metricsAddr))
 	}
 
-	defer func(conn *pgx.Conn, ctx context.Context) {
-		err := conn.Close(ctx)
+	var cfg *config.Config
+
+	if configPath != "" {
+		loaded, err := config.Load(configPath)
 
 		if err != nil {
-			log.Fatalf("Failed to close database connection")
+			fatalf("%v", err)
 		}
-	}(conn, context.Background())
 
-	var ctx context.Context
-	var cancel context.CancelFunc
+		cfg = loaded
+	} else {
+		if dbName == "" || len(tables) == 0 {
+			fatalf("All --dbName and --table arguments are required, or pass --config")
+		}
 
-	ctx, cancel = context.WithTimeout(context.Background(), 10*time.Second)
-	defer cancel()
+		cfg = &config.Config{}
 
-	if err := conn.Ping(ctx); err != nil {
-		log.Fatalf("ERROR: Database ping failed: %v\n", err)
-	}
+		for _, table := range tables {
+			parts := splitTableFlag(table)
 
-	log.Println("Connected to the database successfully")
+			if parts == nil {
+				fatalf("Invalid format: %s", table)
+			}
 
-	for _, table := range tables {
-		parts := strings.Split(table, ":")
-		partCnt := len(parts)
+			days := 0
 
-		if partCnt < 1 || partCnt > 3 {
-			log.Println("Invalid format: ", parts)
-			continue
-		}
+			if parts.days != "" {
+				parsed, err := parseDays(parts.days)
 
-		tableName = parts[0]
+				if err != nil {
+					fatalf("Error parsing days integer value: %v", err)
+				}
 
-		if !pg.IsValidTName(tableName) {
-			log.Fatalf("Invalid table name: %s\n", table)
-		}
+				days = parsed
+			}
 
-		if partCnt > 1 {
-			timestampColumn = parts[1]
+			timestampColumn := parts.timestampColumn
 
 			if timestampColumn == "" {
 				timestampColumn = "created_at"
-			} else {
-				if !pg.IsValidTName(timestampColumn) {
-					log.Fatalf("Invalid timestamp column name: %s\n", table)
-				}
 			}
 
-			if partCnt > 2 {
-				days, err = strconv.Atoi(parts[2])
+			single := config.SingleTableConfig(
+				host, port, sslMode, dbName, parts.table.Name, parts.table.Schema, timestampColumn, days, batchSize,
+				statementTimeout, lockTimeout, config.VacuumMode(vacuumAfter),
+			)
 
-				if err != nil {
-					log.Fatalln("Error parsing days integer value: ", err)
-				}
+			if len(cfg.Databases) == 0 {
+				cfg.Databases = single.Databases
 			} else {
-				days = 0
+				cfg.Databases[0].Tables = append(cfg.Databases[0].Tables, single.Databases[0].Tables...)
 			}
-		} else {
-			timestampColumn = "created_at"
 		}
 
-		log.Printf("Cleaning up table %s by column %s for the records older than %d days with batch=%d\n",
-			tableName,
-			timestampColumn,
-			days,
-			batchSize,
-		)
-
-		args := []interface{}{now.AddDate(0, 0, -days)}
-
-		if batchSize > 0 {
-			args = append(args, batchSize)
+		if err := cfg.Validate(); err != nil {
+			fatalf("%v", err)
 		}
+	}
 
-		subquery := fmt.Sprintf(
-			`SELECT ctid FROM %s WHERE %s < $1 ORDER BY %s`,
-			tableName,
-			timestampColumn,
-			timestampColumn,
-		)
+	opts := runOptions{
+		timeout:       timeout,
+		partitionMode: partitionMode,
+		dryRun:        dryRun,
+		dryRunSample:  dryRunSample,
+		workers:       workers,
+		connect: connectOptions{
+			retries:        connectRetries,
+			backoffInitial: connectBackoffInitial,
+			backoffMax:     connectBackoffMax,
+			backoffJitter:  connectBackoffJitter,
+		},
+		bloatReport: bloatReport,
+	}
 
-		if batchSize > 0 {
-			subquery += " LIMIT $2"
-		}
+	ctx := context.Background()
 
-		query := fmt.Sprintf(`DELETE FROM %s WHERE ctid IN (%s);`, tableName, subquery)
+	for _, db := range cfg.Databases {
+		cleanupDatabase(ctx, now, db, opts)
+	}
 
-		for {
-			if timeout > 0 {
-				ctx, cancel = context.WithTimeout(context.Background(), timeout)
-			} else {
-				ctx = context.WithoutCancel(context.Background())
-				cancel = nil
-			}
+	metrics.RecordLastRun(now)
 
-			tx, err := conn.BeginTx(ctx, pgx.TxOptions{})
+	if pushgatewayURL != "" {
+		if err := metrics.Push(ctx, pushgatewayURL, "pgmaint"); err != nil {
+			logger.Warn(fmt.Sprintf("Failed to push metrics to %s: %v", pushgatewayURL, err))
+		}
+	}
+}
 
-			if err != nil {
-				log.Fatalf("ERROR: Failed to begin transaction: %v\n", err)
-			}
+// tableFlagParts is the parsed form of a --table [schema.]table:[column]:[days] flag.
+type tableFlagParts struct {
+	table           pg.Identifier
+	timestampColumn string
+	days            string
+}
 
-			log.Println(query, args)
+// splitTableFlag parses the legacy [schema.]table:[timestampColumn[:days]]
+// flag value, returning nil if the format is invalid.
+func splitTableFlag(value string) *tableFlagParts {
+	parts := strings.Split(value, ":")
+	partCnt := len(parts)
 
-			result, err := conn.Exec(ctx, query, args...)
+	if partCnt < 1 || partCnt > 3 {
+		return nil
+	}
 
-			if err != nil {
-				_ = tx.Rollback(ctx)
-				log.Fatalf("ERROR: Failed to execute query: %v\n", err)
-			}
+	table, err := pg.ParseQualifiedName(parts[0])
 
-			rowsAffected := result.RowsAffected()
+	if err != nil {
+		return nil
+	}
 
-			if err := tx.Commit(ctx); err != nil {
-				log.Fatalf("ERROR: Failed to commit transaction: %v\n", err)
-			}
+	result := &tableFlagParts{table: table}
 
-			if cancel != nil {
-				cancel()
-			}
+	if partCnt > 1 {
+		if parts[1] != "" && !pg.IsValidIdentifierPart(parts[1]) {
+			return nil
+		}
 
-			if rowsAffected == 0 {
-				log.Println("No more rows to delete. Exiting.")
+		result.timestampColumn = parts[1]
+	}
 
-				break
-			}
+	if partCnt > 2 {
+		result.days = parts[2]
+	}
 
-			log.Printf("Deleted %d rows\n", rowsAffected)
+	return result
+}
 
-			if batchSize == 0 {
-				break
-			}
-		}
-	}
+// parseDays parses the days component of a --table flag.
+func parseDays(value string) (int, error) {
+	return strconv.Atoi(value)
 }
@@ -0,0 +1,223 @@
+// Package config loads the optional multi-database, multi-table cleanup
+// policy file (--config policies.yaml) that replaces repeated --table
+// flags when a single invocation needs to cover a fleet of databases.
+package config
+
+import (
+	"fmt"
+	"os"
+	"time"
+
+	"gopkg.in/yaml.v3"
+
+	"github.com/bixority/pg-maintenance/internal/module/pg"
+)
+
+// VacuumMode selects what, if anything, runs against a table after its
+// cleanup finishes.
+type VacuumMode string
+
+const (
+	VacuumNone    VacuumMode = "none"
+	VacuumAnalyze VacuumMode = "analyze"
+	VacuumVacuum  VacuumMode = "vacuum"
+	VacuumFull    VacuumMode = "full"
+)
+
+// TablePolicy describes how a single table should be cleaned up.
+type TablePolicy struct {
+	Schema           string        `yaml:"schema"`
+	Name             string        `yaml:"name"`
+	TimestampColumn  string        `yaml:"timestamp_column"`
+	RetentionDays    int           `yaml:"retention_days"`
+	BatchSize        int           `yaml:"batch_size"`
+	StatementTimeout time.Duration `yaml:"statement_timeout"`
+	LockTimeout      time.Duration `yaml:"lock_timeout"`
+	WhereExtra       string        `yaml:"where_extra"`
+	VacuumAfter      VacuumMode    `yaml:"vacuum_after"`
+}
+
+// DatabasePolicy describes one target database and the tables to clean up
+// in it.
+type DatabasePolicy struct {
+	Host        string        `yaml:"host"`
+	Port        int           `yaml:"port"`
+	SSLMode     string        `yaml:"sslmode"`
+	DBName      string        `yaml:"name"`
+	UsernameEnv string        `yaml:"username_env"`
+	PasswordEnv string        `yaml:"password_env"`
+	Tables      []TablePolicy `yaml:"tables"`
+}
+
+// Config is the top-level shape of a --config policies.yaml file.
+type Config struct {
+	Databases []DatabasePolicy `yaml:"databases"`
+}
+
+// Load reads and validates a policy file at path.
+func Load(path string) (*Config, error) {
+	data, err := os.ReadFile(path)
+
+	if err != nil {
+		return nil, fmt.Errorf("failed to read config %s: %w", path, err)
+	}
+
+	var cfg Config
+
+	if err := yaml.Unmarshal(data, &cfg); err != nil {
+		return nil, fmt.Errorf("failed to parse config %s: %w", path, err)
+	}
+
+	applyDefaults(&cfg)
+
+	if err := cfg.Validate(); err != nil {
+		return nil, err
+	}
+
+	return &cfg, nil
+}
+
+// applyDefaults fills in the same defaults the CLI flags use, so a
+// minimal config file behaves the same way the --table shortcut would.
+func applyDefaults(cfg *Config) {
+	for di := range cfg.Databases {
+		db := &cfg.Databases[di]
+
+		if db.Port == 0 {
+			db.Port = 5432
+		}
+
+		if db.SSLMode == "" {
+			db.SSLMode = "require"
+		}
+
+		if db.UsernameEnv == "" {
+			db.UsernameEnv = "DB_USERNAME"
+		}
+
+		if db.PasswordEnv == "" {
+			db.PasswordEnv = "DB_PASSWORD"
+		}
+
+		for ti := range db.Tables {
+			table := &db.Tables[ti]
+
+			if table.TimestampColumn == "" {
+				table.TimestampColumn = "created_at"
+			}
+
+			if table.VacuumAfter == "" {
+				table.VacuumAfter = VacuumNone
+			}
+		}
+	}
+}
+
+// Validate checks that every identifier in the config is safe to
+// interpolate into a query and that every database has at least one
+// table policy.
+func (c *Config) Validate() error {
+	if len(c.Databases) == 0 {
+		return fmt.Errorf("config must declare at least one database")
+	}
+
+	for _, db := range c.Databases {
+		if db.DBName == "" {
+			return fmt.Errorf("database entry is missing a name")
+		}
+
+		if len(db.Tables) == 0 {
+			return fmt.Errorf("database %s must declare at least one table", db.DBName)
+		}
+
+		for _, table := range db.Tables {
+			if table.Name == "" {
+				return fmt.Errorf("database %s has a table entry with no name", db.DBName)
+			}
+
+			if !pg.IsValidIdentifierPart(table.Name) {
+				return fmt.Errorf("database %s: invalid table name %q", db.DBName, table.Name)
+			}
+
+			if table.Schema != "" && !pg.IsValidIdentifierPart(table.Schema) {
+				return fmt.Errorf("database %s: invalid schema name %q", db.DBName, table.Schema)
+			}
+
+			if !pg.IsValidIdentifierPart(table.TimestampColumn) {
+				return fmt.Errorf(
+					"database %s, table %s: invalid timestamp column %q",
+					db.DBName, table.Name, table.TimestampColumn,
+				)
+			}
+
+			switch table.VacuumAfter {
+			case VacuumNone, VacuumAnalyze, VacuumVacuum, VacuumFull:
+			default:
+				return fmt.Errorf(
+					"database %s, table %s: invalid vacuum_after %q",
+					db.DBName, table.Name, table.VacuumAfter,
+				)
+			}
+		}
+	}
+
+	return nil
+}
+
+// Identifier returns t's table name as a pg.Identifier, ready to be
+// rendered through pg.Quote.
+func (t TablePolicy) Identifier() pg.Identifier {
+	return pg.Identifier{Schema: t.Schema, Name: t.Name}
+}
+
+// QualifiedName returns t's table name as a properly double-quoted,
+// schema-qualified identifier suitable for direct interpolation into a
+// query, e.g. `"analytics"."events"`.
+func (t TablePolicy) QualifiedName() string {
+	return pg.Quote(t.Identifier())
+}
+
+// SingleTableConfig synthesizes a single-database, single-table Config
+// from the legacy --table flag form, so the rest of the tool can treat
+// both invocation styles identically.
+func SingleTableConfig(
+	host string,
+	port int,
+	sslMode string,
+	dbName string,
+	tableName string,
+	schema string,
+	timestampColumn string,
+	days int,
+	batchSize int,
+	statementTimeout time.Duration,
+	lockTimeout time.Duration,
+	vacuumAfter VacuumMode,
+) *Config {
+	cfg := &Config{
+		Databases: []DatabasePolicy{
+			{
+				Host:    host,
+				Port:    port,
+				SSLMode: sslMode,
+				DBName:  dbName,
+				Tables: []TablePolicy{
+					{
+						Schema:           schema,
+						Name:             tableName,
+						TimestampColumn:  timestampColumn,
+						RetentionDays:    days,
+						BatchSize:        batchSize,
+						StatementTimeout: statementTimeout,
+						LockTimeout:      lockTimeout,
+						VacuumAfter:      vacuumAfter,
+					},
+				},
+			},
+		},
+	}
+
+	applyDefaults(cfg)
+
+	return cfg
+}
@@ -0,0 +1,113 @@
+// Package metrics exposes the Prometheus collectors for pg-maintenance
+// cleanup runs and the optional /metrics HTTP server and Pushgateway
+// client used to publish them.
+package metrics
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"os"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+	"github.com/prometheus/client_golang/prometheus/push"
+)
+
+// logger is this package's structured logger, kept separate from the main
+// command's so Serve can report a failed bind/serve even though it runs in
+// its own background goroutine.
+var logger = slog.New(slog.NewJSONHandler(os.Stdout, nil))
+
+// Registry is the registry every collector below is registered against,
+// kept separate from the global default registry so embedding this
+// package doesn't pollute a host process's own metrics.
+var Registry = prometheus.NewRegistry()
+
+var (
+	RowsDeletedTotal = promauto.With(Registry).NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "pgmaint_rows_deleted_total",
+			Help: "Total rows deleted by cleanup batches.",
+		},
+		[]string{"db", "schema", "table"},
+	)
+
+	BatchesTotal = promauto.With(Registry).NewCounter(
+		prometheus.CounterOpts{
+			Name: "pgmaint_batches_total",
+			Help: "Total number of DELETE batches executed.",
+		},
+	)
+
+	BatchDurationSeconds = promauto.With(Registry).NewHistogram(
+		prometheus.HistogramOpts{
+			Name:    "pgmaint_batch_duration_seconds",
+			Help:    "Duration of individual DELETE batches.",
+			Buckets: prometheus.DefBuckets,
+		},
+	)
+
+	LastRunTimestampSeconds = promauto.With(Registry).NewGauge(
+		prometheus.GaugeOpts{
+			Name: "pgmaint_last_run_timestamp_seconds",
+			Help: "Unix timestamp of the last completed cleanup run.",
+		},
+	)
+
+	ErrorsTotal = promauto.With(Registry).NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "pgmaint_errors_total",
+			Help: "Total errors encountered, by kind.",
+		},
+		[]string{"kind"},
+	)
+
+	TableSizeBytes = promauto.With(Registry).NewGaugeVec(
+		prometheus.GaugeOpts{
+			Name: "pgmaint_table_size_bytes",
+			Help: "pg_total_relation_size of a table, sampled before and after its post-cleanup VACUUM; not sampled when no VACUUM runs.",
+		},
+		[]string{"db", "schema", "table", "phase"},
+	)
+)
+
+// Serve starts an HTTP server exposing the registry on addr at /metrics.
+// It runs in the background; the caller is responsible for shutting it
+// down (e.g. via the returned *http.Server) before the process exits.
+func Serve(addr string) *http.Server {
+	mux := http.NewServeMux()
+	mux.Handle("/metrics", promhttp.HandlerFor(Registry, promhttp.HandlerOpts{}))
+
+	server := &http.Server{Addr: addr, Handler: mux}
+
+	go func() {
+		if err := server.ListenAndServe(); err != nil && !errors.Is(err, http.ErrServerClosed) {
+			logger.Error(fmt.Sprintf("Metrics server on %s failed: %v", addr, err))
+		}
+	}()
+
+	return server
+}
+
+// Push pushes every collector in Registry to the Pushgateway at url under
+// jobName, for one-shot cron invocations where no scrape would otherwise
+// happen before the process exits.
+func Push(ctx context.Context, url string, jobName string) error {
+	pusher := push.New(url, jobName).Gatherer(Registry)
+
+	if err := pusher.PushContext(ctx); err != nil {
+		return fmt.Errorf("failed to push metrics to %s: %w", url, err)
+	}
+
+	return nil
+}
+
+// RecordLastRun stamps LastRunTimestampSeconds with now.
+func RecordLastRun(now time.Time) {
+	LastRunTimestampSeconds.Set(float64(now.Unix()))
+}
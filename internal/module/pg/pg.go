@@ -1,11 +1,75 @@
+// Package pg holds small, dependency-free helpers for validating and
+// safely quoting Postgres identifiers shared by every cleanup command.
 package pg
 
-func IsValidTableName(name string) bool {
-	for _, r := range name {
-		if !(r >= 'A' && r <= 'Z') && !(r >= 'a' && r <= 'z') && !(r >= '0' && r <= '9') && r != '_' {
-			return false
+import (
+	"fmt"
+	"strings"
+)
+
+// maxIdentifierLength mirrors Postgres' NAMEDATALEN-1 default, beyond
+// which identifiers are silently truncated by the server.
+const maxIdentifierLength = 63
+
+// Identifier is a possibly schema-qualified Postgres object name, e.g.
+// "analytics"."events" or the unqualified "events".
+type Identifier struct {
+	Schema string
+	Name   string
+}
+
+// IsValidIdentifierPart reports whether name is legal inside a
+// double-quoted Postgres identifier: non-empty, free of NUL bytes, and no
+// longer than Postgres would silently truncate to.
+func IsValidIdentifierPart(name string) bool {
+	if name == "" || len(name) > maxIdentifierLength {
+		return false
+	}
+
+	return !strings.ContainsRune(name, 0)
+}
+
+// ParseQualifiedName parses a "schema.table" or bare "table" reference,
+// validating each part independently. Both parts may use any character
+// legal inside a quoted identifier (including case and punctuation) since
+// the result is always rendered through Quote.
+func ParseQualifiedName(raw string) (Identifier, error) {
+	parts := strings.SplitN(raw, ".", 2)
+
+	if len(parts) == 1 {
+		if !IsValidIdentifierPart(parts[0]) {
+			return Identifier{}, fmt.Errorf("invalid identifier: %q", raw)
 		}
+
+		return Identifier{Name: parts[0]}, nil
 	}
 
-	return true
+	schema, name := parts[0], parts[1]
+
+	if !IsValidIdentifierPart(schema) {
+		return Identifier{}, fmt.Errorf("invalid schema in %q", raw)
+	}
+
+	if !IsValidIdentifierPart(name) {
+		return Identifier{}, fmt.Errorf("invalid table name in %q", raw)
+	}
+
+	return Identifier{Schema: schema, Name: name}, nil
+}
+
+// Quote renders id as a properly double-quoted, escaped identifier, e.g.
+// Identifier{Schema: "analytics", Name: "Events"} -> `"analytics"."Events"`.
+// Embedded double quotes are doubled per the Postgres quoting rules.
+func Quote(id Identifier) string {
+	if id.Schema == "" {
+		return quotePart(id.Name)
+	}
+
+	return quotePart(id.Schema) + "." + quotePart(id.Name)
+}
+
+// quotePart double-quotes a single identifier part, escaping embedded
+// quotes by doubling them.
+func quotePart(part string) string {
+	return `"` + strings.ReplaceAll(part, `"`, `""`) + `"`
 }
@@ -0,0 +1,93 @@
+package pg
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestIsValidIdentifierPart(t *testing.T) {
+	tests := []struct {
+		name string
+		in   string
+		want bool
+	}{
+		{"empty", "", false},
+		{"simple", "events", true},
+		{"mixed case", "Events", true},
+		{"embedded quote", `ev"ents`, true},
+		{"nul byte", "ev\x00ents", false},
+		{"exactly max length", strings.Repeat("a", maxIdentifierLength), true},
+		{"over max length", strings.Repeat("a", maxIdentifierLength+1), false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := IsValidIdentifierPart(tt.in); got != tt.want {
+				t.Errorf("IsValidIdentifierPart(%q) = %v, want %v", tt.in, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestParseQualifiedName(t *testing.T) {
+	tests := []struct {
+		name    string
+		raw     string
+		want    Identifier
+		wantErr bool
+	}{
+		{"bare table", "events", Identifier{Name: "events"}, false},
+		{"schema qualified", "analytics.events", Identifier{Schema: "analytics", Name: "events"}, false},
+		{"mixed case", "Analytics.Events", Identifier{Schema: "Analytics", Name: "Events"}, false},
+		{"embedded quote in name", `analytics.ev"ents`, Identifier{Schema: "analytics", Name: `ev"ents`}, false},
+		{"extra dot folds into name", "schema.table.extra", Identifier{Schema: "schema", Name: "table.extra"}, false},
+		{"empty", "", Identifier{}, true},
+		{"empty schema", ".events", Identifier{}, true},
+		{"empty name", "analytics.", Identifier{}, true},
+		{"nul byte in bare name", "ev\x00ents", Identifier{}, true},
+		{"schema over max length", strings.Repeat("a", maxIdentifierLength+1) + ".events", Identifier{}, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := ParseQualifiedName(tt.raw)
+
+			if tt.wantErr {
+				if err == nil {
+					t.Fatalf("ParseQualifiedName(%q) = %+v, nil, want error", tt.raw, got)
+				}
+
+				return
+			}
+
+			if err != nil {
+				t.Fatalf("ParseQualifiedName(%q) returned unexpected error: %v", tt.raw, err)
+			}
+
+			if got != tt.want {
+				t.Errorf("ParseQualifiedName(%q) = %+v, want %+v", tt.raw, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestQuote(t *testing.T) {
+	tests := []struct {
+		name string
+		in   Identifier
+		want string
+	}{
+		{"unqualified", Identifier{Name: "events"}, `"events"`},
+		{"schema qualified", Identifier{Schema: "analytics", Name: "events"}, `"analytics"."events"`},
+		{"embedded quote doubled", Identifier{Name: `ev"ents`}, `"ev""ents"`},
+		{"embedded quote in schema doubled", Identifier{Schema: `an"alytics`, Name: "events"}, `"an""alytics"."events"`},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := Quote(tt.in); got != tt.want {
+				t.Errorf("Quote(%+v) = %q, want %q", tt.in, got, tt.want)
+			}
+		})
+	}
+}
@@ -10,6 +10,8 @@ import (
 	"time"
 
 	_ "github.com/lib/pq"
+
+	"github.com/bixority/pg-maintenance/internal/module/pg"
 )
 
 func main() {
@@ -33,11 +35,15 @@ func main() {
 		log.Fatalf("Both --table and --dtcrea arguments are required")
 	}
 
-	// Validate table name (it must be alphanumeric or underscore)
-	if !isValidTableName(tableName) {
+	// Validate and parse the (optionally schema-qualified) table name
+	table, err := pg.ParseQualifiedName(tableName)
+
+	if err != nil {
 		log.Fatalf("Invalid table name: %s", tableName)
 	}
 
+	quotedTable := pg.Quote(table)
+
 	// Open database connection (use connection pooling)
 	db, err := sql.Open("postgres", dbURL)
 	if err != nil {
@@ -56,7 +62,7 @@ func main() {
 	// Perform the deletion in batches if specified
 	for {
 		// Prepare the SQL query using parameterized query for dtcrea
-		query := fmt.Sprintf(`DELETE FROM "%s" WHERE dtcrea < $1`, tableName)
+		query := fmt.Sprintf(`DELETE FROM %s WHERE dtcrea < $1`, quotedTable)
 		if batchSize > 0 {
 			query += fmt.Sprintf(" LIMIT %d", batchSize)
 		}
@@ -86,13 +92,3 @@ func main() {
 		}
 	}
 }
-
-// isValidTableName checks if the table name contains only allowed characters (alphanumeric and underscore)
-func isValidTableName(name string) bool {
-	for _, r := range name {
-		if !(r >= 'A' && r <= 'Z') && !(r >= 'a' && r <= 'z') && !(r >= '0' && r <= '9') && r != '_' {
-			return false
-		}
-	}
-	return true
-}